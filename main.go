@@ -2,16 +2,54 @@ package main
 
 import (
 	"bufio"
+	"fmt"
 	"image"
 	"image/color"
 	"image/png"
 	"math"
+	"math/rand"
 	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 )
 
 const (
 	totalWidth  = 1024
 	totalHeight = 768
+
+	defaultSamplesPerPixel = 8
+	defaultTileSize        = 32
+	defaultMaxDepth        = 4
+
+	// minBounces is how many path segments a path-traced ray always
+	// survives before Russian roulette starts terminating it early.
+	minBounces = 4
+
+	// defaultVFov matches the original fixed fov=1.0 (in radians) camera.
+	defaultVFov = 1.0 * 180 / math.Pi
+
+	defaultGamma          = 2.2
+	defaultBloomThreshold = 1.0
+	defaultBloomStrength  = 0.25
+	bloomKernelSize       = 5
+	bloomIterations       = 3
+)
+
+// Integrator selects how Renderer.Render shades a primary ray.
+type Integrator int
+
+const (
+	// IntegratorWhitted is the classic recursive reflect/refract/shadow
+	// integrator implemented by castRay.
+	IntegratorWhitted Integrator = iota
+	// IntegratorPathTrace is a Monte Carlo path tracer with cosine-weighted
+	// hemisphere sampling and Russian-roulette termination, implemented
+	// by pathTrace. It requires emissive materials to have any light
+	// sources, since it does not evaluate Light structs directly.
+	IntegratorPathTrace
 )
 
 var (
@@ -54,6 +92,23 @@ type vec4f struct {
 	T float64
 }
 
+// Hittable is anything a ray can intersect: Sphere, Triangle, and
+// CheckerboardPlane all implement it, which lets sceneIntersect stay
+// agnostic to the concrete primitive types in a Scene.
+type Hittable interface {
+	// RayIntersect reports whether the ray (orig, dir) hits the
+	// primitive and, if so, the distance along dir, the surface normal
+	// at the hit point, and the primitive's material.
+	RayIntersect(orig, dir *vec3f) (bool, float64, *vec3f, *Material)
+}
+
+// Bounded is a Hittable with a finite axis-aligned bounding box, which is
+// what BVH construction requires. CheckerboardPlane does not implement it.
+type Bounded interface {
+	Hittable
+	Bounds() AABB
+}
+
 // Sphere is represented by a vec3f center and a float64 radius
 type Sphere struct {
 	Center   *vec3f
@@ -67,6 +122,11 @@ type Material struct {
 	DiffuseColor     *vec3f
 	SpecularExponent float64
 	RefractiveIndex  float64
+
+	// Emissive, when non-nil, makes the surface a light source for the
+	// path-tracing integrator: a ray that hits it returns this value
+	// times the ray's accumulated throughput instead of recursing.
+	Emissive *vec3f
 }
 
 // NewMaterial returns a properly initialized Material
@@ -77,84 +137,953 @@ func NewMaterial() *Material {
 	}
 }
 
+// defaultMeshMaterial returns the flat gray material LoadOBJ assigns to
+// triangles that don't otherwise specify appearance, so that a freshly
+// loaded Mesh is renderable without a caller having to set DiffuseColor
+// first.
+func defaultMeshMaterial() *Material {
+	material := NewMaterial()
+	material.DiffuseColor = &vec3f{0.5, 0.5, 0.5}
+	return material
+}
+
 // Light source
 type Light struct {
 	Position  *vec3f
 	Intensity float64
 }
 
-func main() {
-	spheres := []*Sphere{
-		{Center: &vec3f{-3, 0, -16}, Radius: 2, Material: ivory},
-		{Center: &vec3f{-1, -1.5, -12}, Radius: 2, Material: glass},
-		{Center: &vec3f{1.5, -0.5, -18}, Radius: 3, Material: redRubber},
-		{Center: &vec3f{7, 5, -18}, Radius: 4, Material: mirror},
-	}
-	lights := []*Light{
-		{Position: &vec3f{-20, 20, 20}, Intensity: 1.5},
-		{Position: &vec3f{30, 50, -25}, Intensity: 1.8},
-		{Position: &vec3f{30, 20, 30}, Intensity: 1.7},
-	}
-	rect := image.Rect(0, 0, totalWidth, totalHeight)
-	img := image.NewRGBA(rect)
-
-	fov := 1.0
-
-	for j := 0; j < totalHeight; j++ {
-		y := -(2*(float64(j)+0.5)/float64(totalHeight) - 1) * math.Tan(fov/2.0)
-		for i := 0; i < totalWidth; i++ {
-			x := (2*(float64(i)+0.5)/float64(totalWidth) - 1) * math.Tan(fov/2.0) * totalWidth / float64(totalHeight)
-			dir := (&vec3f{x, y, -1}).Normalize()
-			colorVec := castRay(&vec3f{0, 0, 0}, dir, spheres, lights, 0)
-			rgba := color.RGBA{
+// Triangle is a flat primitive described by three vertices, wound
+// counter-clockwise when viewed from the side the normal points to.
+type Triangle struct {
+	V0, V1, V2 *vec3f
+	Material   *Material
+}
+
+// RayIntersect implements the Möller–Trumbore ray/triangle intersection
+// algorithm.
+func (t Triangle) RayIntersect(orig, dir *vec3f) (bool, float64, *vec3f, *Material) {
+	const epsilon = 1e-8
+
+	edge1 := t.V1.Subtract(t.V0)
+	edge2 := t.V2.Subtract(t.V0)
+	h := dir.Cross(edge2)
+	a := edge1.Multiply(h)
+	if math.Abs(a) < epsilon {
+		return false, 0, nil, nil
+	}
+
+	f := 1 / a
+	s := orig.Subtract(t.V0)
+	u := f * s.Multiply(h)
+	if u < 0 || u > 1 {
+		return false, 0, nil, nil
+	}
+
+	q := s.Cross(edge1)
+	v := f * dir.Multiply(q)
+	if v < 0 || u+v > 1 {
+		return false, 0, nil, nil
+	}
+
+	dist := f * edge2.Multiply(q)
+	if dist < epsilon {
+		return false, 0, nil, nil
+	}
+
+	n := edge1.Cross(edge2).Normalize()
+	if n.Multiply(dir) > 0 {
+		n = n.MultiplyF(-1)
+	}
+	return true, dist, n, t.Material
+}
+
+// Bounds implements Bounded for Triangle.
+func (t Triangle) Bounds() AABB {
+	min := &vec3f{
+		math.Min(t.V0.X, math.Min(t.V1.X, t.V2.X)),
+		math.Min(t.V0.Y, math.Min(t.V1.Y, t.V2.Y)),
+		math.Min(t.V0.Z, math.Min(t.V1.Z, t.V2.Z)),
+	}
+	max := &vec3f{
+		math.Max(t.V0.X, math.Max(t.V1.X, t.V2.X)),
+		math.Max(t.V0.Y, math.Max(t.V1.Y, t.V2.Y)),
+		math.Max(t.V0.Z, math.Max(t.V1.Z, t.V2.Z)),
+	}
+	return AABB{Min: min, Max: max}
+}
+
+// Mesh owns the triangles loaded from a single OBJ file.
+type Mesh struct {
+	Triangles []Triangle
+}
+
+// LoadOBJ parses a Wavefront OBJ file into a Mesh. It supports `v` vertex
+// records and `f` face records, including `v/vt/vn` triplets (the texture
+// and normal indices are ignored), fan-triangulation of polygons with more
+// than three vertices, and both 1-based and negative (relative) vertex
+// indices. Triangles are loaded with a flat gray default material (see
+// defaultMeshMaterial); callers that care about appearance should overwrite
+// mesh.Triangles[i].Material.
+func LoadOBJ(path string) (*Mesh, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var vertices []*vec3f
+	mesh := &Mesh{}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		switch fields[0] {
+		case "v":
+			if len(fields) < 4 {
+				return nil, fmt.Errorf("gotinyraytracer: malformed vertex line %q", scanner.Text())
+			}
+			v, err := parseVertex(fields[1:4])
+			if err != nil {
+				return nil, err
+			}
+			vertices = append(vertices, v)
+		case "f":
+			indices := make([]int, 0, len(fields)-1)
+			for _, token := range fields[1:] {
+				idx, err := parseFaceIndex(token, len(vertices))
+				if err != nil {
+					return nil, err
+				}
+				indices = append(indices, idx)
+			}
+			for i := 1; i < len(indices)-1; i++ {
+				mesh.Triangles = append(mesh.Triangles, Triangle{
+					V0:       vertices[indices[0]],
+					V1:       vertices[indices[i]],
+					V2:       vertices[indices[i+1]],
+					Material: defaultMeshMaterial(),
+				})
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return mesh, nil
+}
+
+func parseVertex(fields []string) (*vec3f, error) {
+	coords := make([]float64, 3)
+	for i, field := range fields {
+		c, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("gotinyraytracer: invalid vertex coordinate %q: %w", field, err)
+		}
+		coords[i] = c
+	}
+	return &vec3f{coords[0], coords[1], coords[2]}, nil
+}
+
+// parseFaceIndex parses the vertex index out of an `f` record token, which
+// may be a bare index or a `v/vt/vn` triplet, and resolves it (1-based, or
+// negative/relative to the vertices seen so far) into a 0-based index into
+// vertices.
+func parseFaceIndex(token string, vertexCount int) (int, error) {
+	vIdx := strings.SplitN(token, "/", 2)[0]
+	idx, err := strconv.Atoi(vIdx)
+	if err != nil {
+		return 0, fmt.Errorf("gotinyraytracer: invalid face index %q: %w", token, err)
+	}
+	if idx < 0 {
+		idx = vertexCount + idx + 1
+	}
+	if idx < 1 || idx > vertexCount {
+		return 0, fmt.Errorf("gotinyraytracer: face index %d out of range (%d vertices)", idx, vertexCount)
+	}
+	return idx - 1, nil
+}
+
+// CheckerboardPlane is the ground plane at y=Y, clipped to the
+// rectangle where |x|<HalfSize and FarZ<z<NearZ, shaded with an
+// alternating two-tone checker pattern.
+type CheckerboardPlane struct {
+	Y        float64
+	HalfSize float64
+	NearZ    float64
+	FarZ     float64
+}
+
+// RayIntersect implements Hittable for the ground plane.
+func (p *CheckerboardPlane) RayIntersect(orig, dir *vec3f) (bool, float64, *vec3f, *Material) {
+	if math.Abs(dir.Y) < 1e-3 {
+		return false, 0, nil, nil
+	}
+	dist := (p.Y - orig.Y) / dir.Y
+	pt := orig.Add(dir.MultiplyF(dist))
+	if dist <= 0 || math.Abs(pt.X) >= p.HalfSize || pt.Z <= p.FarZ || pt.Z >= p.NearZ {
+		return false, 0, nil, nil
+	}
+
+	material := NewMaterial()
+	if (int(0.5+pt.X+1000)+int(0.5*pt.Z))&1 != 0 {
+		material.DiffuseColor = (&vec3f{1, 1, 1}).MultiplyF(0.3)
+	} else {
+		material.DiffuseColor = (&vec3f{1, 0.7, 0.3}).MultiplyF(0.3)
+	}
+	return true, dist, &vec3f{0, 1, 0}, material
+}
+
+// AABB is an axis-aligned bounding box.
+type AABB struct {
+	Min *vec3f
+	Max *vec3f
+}
+
+// Union returns the smallest AABB containing both a and b.
+func (a AABB) Union(b AABB) AABB {
+	return AABB{
+		Min: &vec3f{
+			math.Min(a.Min.X, b.Min.X),
+			math.Min(a.Min.Y, b.Min.Y),
+			math.Min(a.Min.Z, b.Min.Z),
+		},
+		Max: &vec3f{
+			math.Max(a.Max.X, b.Max.X),
+			math.Max(a.Max.Y, b.Max.Y),
+			math.Max(a.Max.Z, b.Max.Z),
+		},
+	}
+}
+
+// SurfaceArea returns a's surface area, used by the SAH split heuristic.
+func (a AABB) SurfaceArea() float64 {
+	d := a.Max.Subtract(a.Min)
+	return 2 * (d.X*d.Y + d.Y*d.Z + d.Z*d.X)
+}
+
+// component returns v's coordinate along the given axis (0=X, 1=Y, 2=Z).
+func component(v *vec3f, axis int) float64 {
+	switch axis {
+	case 0:
+		return v.X
+	case 1:
+		return v.Y
+	default:
+		return v.Z
+	}
+}
+
+// maxExtentAxis returns the axis (0=X, 1=Y, 2=Z) along which a is widest.
+func (a AABB) maxExtentAxis() int {
+	d := a.Max.Subtract(a.Min)
+	axis := 0
+	widest := d.X
+	if d.Y > widest {
+		axis, widest = 1, d.Y
+	}
+	if d.Z > widest {
+		axis = 2
+	}
+	return axis
+}
+
+// Hit performs the slab test against a ray. It reports whether the ray
+// hits a and, if so, the distance to the near face, which a BVH traversal
+// uses to decide which child to visit first and when the far child can be
+// pruned.
+func (a AABB) Hit(orig, dir *vec3f) (bool, float64) {
+	tmin := math.Inf(-1)
+	tmax := math.Inf(1)
+	for axis := 0; axis < 3; axis++ {
+		invD := 1 / component(dir, axis)
+		t0 := (component(a.Min, axis) - component(orig, axis)) * invD
+		t1 := (component(a.Max, axis) - component(orig, axis)) * invD
+		if invD < 0 {
+			t0, t1 = t1, t0
+		}
+		if t0 > tmin {
+			tmin = t0
+		}
+		if t1 < tmax {
+			tmax = t1
+		}
+	}
+	if tmax < tmin || tmax < 0 {
+		return false, 0
+	}
+	return true, tmin
+}
+
+const (
+	// bvhLeafSize is the maximum number of primitives a BVH leaf holds.
+	bvhLeafSize = 4
+	// bvhTraversalCost and bvhIntersectCost are the relative costs used by
+	// the SAH split heuristic: cost = C_trav + (SA_L*N_L + SA_R*N_R)/SA_parent*C_isect.
+	bvhTraversalCost = 1.0
+	bvhIntersectCost = 1.0
+	bvhMinPrimitives = 8
+)
+
+// bvhNode is one node of a BVH: an interior node has left/right children,
+// a leaf node holds indices into BVH.primitives instead.
+type bvhNode struct {
+	bounds      AABB
+	left, right *bvhNode
+	indices     []int
+}
+
+// BVH accelerates ray intersection against a set of Bounded primitives by
+// recursively partitioning them with a surface-area-heuristic (SAH) split
+// along the axis of greatest extent.
+type BVH struct {
+	primitives []Bounded
+	root       *bvhNode
+}
+
+// NewBVH builds a BVH over primitives.
+func NewBVH(primitives []Bounded) *BVH {
+	bvh := &BVH{primitives: primitives}
+	if len(primitives) == 0 {
+		return bvh
+	}
+	indices := make([]int, len(primitives))
+	for i := range indices {
+		indices[i] = i
+	}
+	bvh.root = bvh.build(indices)
+	return bvh
+}
+
+func (b *BVH) boundsOf(indices []int) AABB {
+	bounds := b.primitives[indices[0]].Bounds()
+	for _, i := range indices[1:] {
+		bounds = bounds.Union(b.primitives[i].Bounds())
+	}
+	return bounds
+}
+
+// build recursively partitions indices into a BVH node, splitting along
+// the parent bounds' widest axis at whichever candidate split minimizes
+// SAH cost. It stops splitting once a leaf holds bvhLeafSize or fewer
+// primitives, or once every candidate split costs more than not splitting
+// at all.
+func (b *BVH) build(indices []int) *bvhNode {
+	bounds := b.boundsOf(indices)
+	if len(indices) <= bvhLeafSize {
+		return &bvhNode{bounds: bounds, indices: indices}
+	}
+
+	axis := bounds.maxExtentAxis()
+	sorted := append([]int(nil), indices...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return component(b.primitives[sorted[i]].Bounds().Centroid(), axis) <
+			component(b.primitives[sorted[j]].Bounds().Centroid(), axis)
+	})
+
+	n := len(sorted)
+	prefix := make([]AABB, n)
+	suffix := make([]AABB, n)
+	prefix[0] = b.primitives[sorted[0]].Bounds()
+	for i := 1; i < n; i++ {
+		prefix[i] = prefix[i-1].Union(b.primitives[sorted[i]].Bounds())
+	}
+	suffix[n-1] = b.primitives[sorted[n-1]].Bounds()
+	for i := n - 2; i >= 0; i-- {
+		suffix[i] = suffix[i+1].Union(b.primitives[sorted[i]].Bounds())
+	}
+
+	parentSA := bounds.SurfaceArea()
+	leafCost := float64(n) * bvhIntersectCost
+	bestCost := math.Inf(1)
+	bestSplit := -1
+	for split := 1; split < n; split++ {
+		nLeft, nRight := float64(split), float64(n-split)
+		cost := bvhTraversalCost + (nLeft*prefix[split-1].SurfaceArea()+nRight*suffix[split].SurfaceArea())/parentSA*bvhIntersectCost
+		if cost < bestCost {
+			bestCost = cost
+			bestSplit = split
+		}
+	}
+
+	if bestSplit <= 0 || bestCost >= leafCost {
+		return &bvhNode{bounds: bounds, indices: sorted}
+	}
+
+	return &bvhNode{
+		bounds: bounds,
+		left:   b.build(sorted[:bestSplit]),
+		right:  b.build(sorted[bestSplit:]),
+	}
+}
+
+// Intersect finds the closest Bounded primitive the ray (orig, dir) hits.
+func (b *BVH) Intersect(orig, dir *vec3f) (bool, float64, *vec3f, *Material) {
+	if b.root == nil {
+		return false, 0, nil, nil
+	}
+	return b.intersectNode(b.root, orig, dir, math.MaxFloat64)
+}
+
+// intersectNode walks the BVH, visiting whichever child's box the ray
+// reaches first and pruning the other child once closest is nearer than
+// that child's near-face distance.
+func (b *BVH) intersectNode(node *bvhNode, orig, dir *vec3f, closest float64) (bool, float64, *vec3f, *Material) {
+	if ok, tmin := node.bounds.Hit(orig, dir); !ok || tmin > closest {
+		return false, 0, nil, nil
+	}
+
+	if node.left == nil && node.right == nil {
+		hitAny := false
+		var hitDist float64
+		var hitN *vec3f
+		var hitMaterial *Material
+		for _, i := range node.indices {
+			intersect, dist, n, material := b.primitives[i].RayIntersect(orig, dir)
+			if intersect && dist < closest {
+				closest = dist
+				hitAny = true
+				hitDist = dist
+				hitN = n
+				hitMaterial = material
+			}
+		}
+		return hitAny, hitDist, hitN, hitMaterial
+	}
+
+	leftHit, leftTmin := node.left.bounds.Hit(orig, dir)
+	rightHit, rightTmin := node.right.bounds.Hit(orig, dir)
+	first, second := node.left, node.right
+	firstHit, firstTmin := leftHit, leftTmin
+	secondHit, secondTmin := rightHit, rightTmin
+	if rightHit && (!leftHit || rightTmin < leftTmin) {
+		first, firstHit, firstTmin = node.right, rightHit, rightTmin
+		second, secondHit, secondTmin = node.left, leftHit, leftTmin
+	}
+
+	hitAny := false
+	var hitDist float64
+	var hitN *vec3f
+	var hitMaterial *Material
+	if firstHit && firstTmin <= closest {
+		if ok, dist, n, material := b.intersectNode(first, orig, dir, closest); ok {
+			hitAny, hitDist, hitN, hitMaterial = true, dist, n, material
+			closest = dist
+		}
+	}
+	if secondHit && secondTmin <= closest {
+		if ok, dist, n, material := b.intersectNode(second, orig, dir, closest); ok {
+			hitAny, hitDist, hitN, hitMaterial = true, dist, n, material
+			closest = dist
+		}
+	}
+	return hitAny, hitDist, hitN, hitMaterial
+}
+
+// Centroid returns the midpoint of a's bounds.
+func (a AABB) Centroid() *vec3f {
+	return a.Min.Add(a.Max).MultiplyF(0.5)
+}
+
+// Scene bundles the primitives and lights that a Renderer casts rays against.
+type Scene struct {
+	Hittables []Hittable
+	Lights    []*Light
+
+	// bvh and unbounded are populated by BuildBVH; when bvh is nil,
+	// sceneIntersect falls back to a linear scan over Hittables.
+	bvh       *BVH
+	unbounded []Hittable
+}
+
+// BuildBVH partitions s.Hittables into primitives with a finite bounding
+// box (accelerated via a BVH) and primitives without one, such as a
+// CheckerboardPlane (tested linearly). It is a no-op, leaving
+// sceneIntersect to fall back to its linear scan, when there are fewer
+// than bvhMinPrimitives bounded primitives to justify the overhead of a
+// tree.
+func (s *Scene) BuildBVH() {
+	var bounded []Bounded
+	var unbounded []Hittable
+	for _, h := range s.Hittables {
+		if b, ok := h.(Bounded); ok {
+			bounded = append(bounded, b)
+		} else {
+			unbounded = append(unbounded, h)
+		}
+	}
+	if len(bounded) < bvhMinPrimitives {
+		return
+	}
+	s.bvh = NewBVH(bounded)
+	s.unbounded = unbounded
+}
+
+// Camera produces primary rays for normalized image coordinates, modeling
+// position, orientation, field of view, and depth of field.
+type Camera struct {
+	Origin        *vec3f
+	LookAt        *vec3f
+	Up            *vec3f
+	VFov          float64 // vertical field of view, in degrees
+	Aspect        float64
+	Aperture      float64
+	FocusDistance float64
+
+	u, v, w                               *vec3f
+	lowerLeftCorner, horizontal, vertical *vec3f
+	lensRadius                            float64
+}
+
+// NewCamera builds a Camera looking from origin toward lookAt, with up
+// orienting the horizon. vfov is the vertical field of view in degrees and
+// aspect is width/height. For depth of field, aperture is the lens
+// diameter and focusDistance is the distance to the plane that's in
+// perfect focus; pass aperture 0 for a pinhole camera with everything in
+// focus.
+func NewCamera(origin, lookAt, up *vec3f, vfov, aspect, aperture, focusDistance float64) *Camera {
+	theta := vfov * math.Pi / 180
+	halfHeight := math.Tan(theta / 2)
+	halfWidth := aspect * halfHeight
+
+	w := origin.Subtract(lookAt).Normalize()
+	u := up.Cross(w).Normalize()
+	v := w.Cross(u)
+
+	c := &Camera{
+		Origin: origin, LookAt: lookAt, Up: up,
+		VFov: vfov, Aspect: aspect, Aperture: aperture, FocusDistance: focusDistance,
+		u: u, v: v, w: w,
+		lensRadius: aperture / 2,
+	}
+	c.horizontal = u.MultiplyF(2 * halfWidth * focusDistance)
+	c.vertical = v.MultiplyF(2 * halfHeight * focusDistance)
+	c.lowerLeftCorner = origin.
+		Subtract(u.MultiplyF(halfWidth * focusDistance)).
+		Subtract(v.MultiplyF(halfHeight * focusDistance)).
+		Subtract(w.MultiplyF(focusDistance))
+	return c
+}
+
+// Ray returns the primary ray for normalized image coordinates s, t, each
+// typically in [0,1] with (0,0) at the bottom-left of the frame. When
+// c.Aperture is non-zero, the ray origin is jittered over a lens disk so
+// that rays toward pixels defocus correctly away from the focus plane.
+func (c *Camera) Ray(s, t float64) (orig, dir *vec3f) {
+	rd := randomInUnitDisk().MultiplyF(c.lensRadius)
+	offset := c.u.MultiplyF(rd.X).Add(c.v.MultiplyF(rd.Y))
+	orig = c.Origin.Add(offset)
+	target := c.lowerLeftCorner.Add(c.horizontal.MultiplyF(s)).Add(c.vertical.MultiplyF(t))
+	dir = target.Subtract(orig).Normalize()
+	return orig, dir
+}
+
+// randomInUnitDisk returns a uniformly random point in the unit disk on
+// the Z=0 plane, used to jitter ray origins over the camera's lens.
+func randomInUnitDisk() *vec3f {
+	for {
+		p := &vec3f{2*rand.Float64() - 1, 2*rand.Float64() - 1, 0}
+		if p.Multiply(p) < 1 {
+			return p
+		}
+	}
+}
+
+// TileResult is a rendered tile, emitted on Renderer.Tiles as progressive
+// preview output while a Render is in flight.
+type TileResult struct {
+	Rect  image.Rectangle
+	Image *image.RGBA
+}
+
+// Renderer owns the framebuffer dimensions and sampling/tracing parameters
+// used to turn a Scene into an *image.RGBA.
+type Renderer struct {
+	Width           int
+	Height          int
+	SamplesPerPixel int
+	TileSize        int
+	NumWorkers      int
+	MaxDepth        int
+	Integrator      Integrator
+	Camera          *Camera
+	PostProcess     PostProcess
+
+	// Tiles, if set before calling Render, receives each tile as it
+	// finishes so callers can display a progressive preview. Render
+	// closes it once every tile has been sent.
+	Tiles chan *TileResult
+}
+
+// ToneMapOperator selects how Develop compresses HDR radiance into [0,1].
+type ToneMapOperator int
+
+const (
+	// ToneMapReinhard is the simple c/(c+1) operator.
+	ToneMapReinhard ToneMapOperator = iota
+	// ToneMapACES is the Narkowicz fit of the ACES filmic curve.
+	ToneMapACES
+)
+
+// PostProcess configures the stages Renderer.Develop runs over the HDR
+// framebuffer: tonemapping, gamma correction, and bloom.
+type PostProcess struct {
+	ToneMap      ToneMapOperator
+	GammaCorrect bool
+
+	Bloom          bool
+	BloomThreshold float64
+	BloomStrength  float64
+}
+
+// NewRenderer returns a Renderer configured with this package's default
+// resolution, quality settings, and camera.
+func NewRenderer() *Renderer {
+	return &Renderer{
+		Width:           totalWidth,
+		Height:          totalHeight,
+		SamplesPerPixel: defaultSamplesPerPixel,
+		TileSize:        defaultTileSize,
+		NumWorkers:      runtime.NumCPU(),
+		MaxDepth:        defaultMaxDepth,
+		Integrator:      IntegratorWhitted,
+		Camera: NewCamera(
+			&vec3f{0, 0, 0}, &vec3f{0, 0, -1}, &vec3f{0, 1, 0},
+			defaultVFov, float64(totalWidth)/float64(totalHeight), 0, 1,
+		),
+		PostProcess: PostProcess{
+			ToneMap:        ToneMapReinhard,
+			GammaCorrect:   true,
+			Bloom:          true,
+			BloomThreshold: defaultBloomThreshold,
+			BloomStrength:  defaultBloomStrength,
+		},
+	}
+}
+
+// Render rasterizes scene into an *image.RGBA, splitting the framebuffer
+// into TileSize×TileSize tiles and dispatching them to NumWorkers
+// goroutines over a job channel. Each pixel is sampled SamplesPerPixel
+// times with a jittered sub-pixel offset and the samples are averaged,
+// giving progressive multi-sample anti-aliasing.
+func (r *Renderer) Render(scene *Scene) *image.RGBA {
+	hdr := make([]vec3f, r.Width*r.Height)
+	preview := image.NewRGBA(image.Rect(0, 0, r.Width, r.Height))
+
+	tiles := r.tiles()
+	jobs := make(chan image.Rectangle, len(tiles))
+	for _, tile := range tiles {
+		jobs <- tile
+	}
+	close(jobs)
+
+	var wg sync.WaitGroup
+	for w := 0; w < r.NumWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for tile := range jobs {
+				r.renderTile(hdr, preview, tile, scene)
+				if r.Tiles != nil {
+					r.Tiles <- &TileResult{Rect: tile, Image: preview}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if r.Tiles != nil {
+		close(r.Tiles)
+	}
+
+	return r.Develop(hdr)
+}
+
+// tiles partitions the framebuffer into TileSize×TileSize rectangles,
+// clipped to the image bounds along the right and bottom edges.
+func (r *Renderer) tiles() []image.Rectangle {
+	var tiles []image.Rectangle
+	for y := 0; y < r.Height; y += r.TileSize {
+		for x := 0; x < r.Width; x += r.TileSize {
+			tiles = append(tiles, image.Rect(x, y, min(x+r.TileSize, r.Width), min(y+r.TileSize, r.Height)))
+		}
+	}
+	return tiles
+}
+
+// renderTile casts SamplesPerPixel jittered rays per pixel within tile and
+// averages the results into hdr (the full-frame HDR buffer), and mirrors a simple clamped preview of the same pixels into
+// preview for progressive display via Renderer.Tiles.
+func (r *Renderer) renderTile(hdr []vec3f, preview *image.RGBA, tile image.Rectangle, scene *Scene) {
+	for j := tile.Min.Y; j < tile.Max.Y; j++ {
+		for i := tile.Min.X; i < tile.Max.X; i++ {
+			accum := &vec3f{}
+			for sample := 0; sample < r.SamplesPerPixel; sample++ {
+				jx, jy := rand.Float64(), rand.Float64()
+				s := (float64(i) + jx) / float64(r.Width)
+				t := 1 - (float64(j)+jy)/float64(r.Height)
+				orig, dir := r.Camera.Ray(s, t)
+				accum = accum.Add(r.trace(orig, dir, scene))
+			}
+			colorVec := accum.MultiplyF(1 / float64(r.SamplesPerPixel))
+			hdr[j*r.Width+i] = *colorVec
+			preview.Set(i, j, color.RGBA{
 				R: uint8(math.Min(math.Max(0, colorVec.X), 1) * 255),
 				G: uint8(math.Min(math.Max(0, colorVec.Y), 1) * 255),
 				B: uint8(math.Min(math.Max(0, colorVec.Z), 1) * 255),
 				A: 255,
+			})
+		}
+	}
+}
+
+// Develop turns the HDR framebuffer hdr (r.Width*r.Height vec3f samples,
+// row-major) into a displayable *image.RGBA by tonemapping, optionally
+// gamma-correcting, and optionally adding a bloom pass, as configured by
+// r.PostProcess.
+func (r *Renderer) Develop(hdr []vec3f) *image.RGBA {
+	developed := make([]vec3f, len(hdr))
+	for i, c := range hdr {
+		developed[i] = *r.tonemap(&c)
+	}
+	if r.PostProcess.GammaCorrect {
+		for i := range developed {
+			developed[i] = *gammaCorrect(&developed[i])
+		}
+	}
+	if r.PostProcess.Bloom {
+		bright := thresholdBright(hdr, r.PostProcess.BloomThreshold)
+		bloom := boxBlur(bright, r.Width, r.Height, bloomKernelSize, bloomIterations)
+		for i := range developed {
+			developed[i] = *developed[i].Add(bloom[i].MultiplyF(r.PostProcess.BloomStrength))
+		}
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, r.Width, r.Height))
+	for j := 0; j < r.Height; j++ {
+		for i := 0; i < r.Width; i++ {
+			c := developed[j*r.Width+i]
+			img.Set(i, j, color.RGBA{
+				R: uint8(math.Min(math.Max(0, c.X), 1) * 255),
+				G: uint8(math.Min(math.Max(0, c.Y), 1) * 255),
+				B: uint8(math.Min(math.Max(0, c.Z), 1) * 255),
+				A: 255,
+			})
+		}
+	}
+	return img
+}
+
+func (r *Renderer) tonemap(c *vec3f) *vec3f {
+	if r.PostProcess.ToneMap == ToneMapACES {
+		return acesTonemap(c)
+	}
+	return reinhardTonemap(c)
+}
+
+// reinhardTonemap applies the simple c/(c+1) operator component-wise.
+func reinhardTonemap(c *vec3f) *vec3f {
+	return &vec3f{c.X / (c.X + 1), c.Y / (c.Y + 1), c.Z / (c.Z + 1)}
+}
+
+// acesTonemap applies the Narkowicz fit of the ACES filmic tonemapping
+// curve component-wise.
+func acesTonemap(c *vec3f) *vec3f {
+	const a, b, cc, d, e = 2.51, 0.03, 2.43, 0.59, 0.14
+	fit := func(x float64) float64 {
+		return math.Max(0, math.Min(1, (x*(a*x+b))/(x*(cc*x+d)+e)))
+	}
+	return &vec3f{fit(c.X), fit(c.Y), fit(c.Z)}
+}
+
+// gammaCorrect applies pow(c, 1/defaultGamma) component-wise.
+func gammaCorrect(c *vec3f) *vec3f {
+	invGamma := 1 / defaultGamma
+	return &vec3f{
+		math.Pow(math.Max(0, c.X), invGamma),
+		math.Pow(math.Max(0, c.Y), invGamma),
+		math.Pow(math.Max(0, c.Z), invGamma),
+	}
+}
+
+// thresholdBright returns a buffer the same size as hdr holding only the
+// pixels whose brightest channel exceeds threshold; every other pixel is
+// black, matching a bright-pass filter.
+func thresholdBright(hdr []vec3f, threshold float64) []vec3f {
+	bright := make([]vec3f, len(hdr))
+	for i, c := range hdr {
+		if c.X > threshold || c.Y > threshold || c.Z > threshold {
+			bright[i] = c
+		}
+	}
+	return bright
+}
+
+// boxBlur repeatedly box-blurs buf (a w×h, row-major grid of vec3f) with a
+// kernel×kernel window, for the given number of iterations.
+func boxBlur(buf []vec3f, w, h, kernel, iterations int) []vec3f {
+	radius := kernel / 2
+	src := buf
+	for iter := 0; iter < iterations; iter++ {
+		dst := make([]vec3f, len(src))
+		for j := 0; j < h; j++ {
+			for i := 0; i < w; i++ {
+				var sum vec3f
+				count := 0
+				for dy := -radius; dy <= radius; dy++ {
+					ny := j + dy
+					if ny < 0 || ny >= h {
+						continue
+					}
+					for dx := -radius; dx <= radius; dx++ {
+						nx := i + dx
+						if nx < 0 || nx >= w {
+							continue
+						}
+						c := src[ny*w+nx]
+						sum.X += c.X
+						sum.Y += c.Y
+						sum.Z += c.Z
+						count++
+					}
+				}
+				dst[j*w+i] = vec3f{sum.X / float64(count), sum.Y / float64(count), sum.Z / float64(count)}
 			}
-			img.Set(i, j, rgba)
 		}
+		src = dst
+	}
+	return src
+}
+
+func main() {
+	scene := &Scene{
+		Hittables: []Hittable{
+			&Sphere{Center: &vec3f{-3, 0, -16}, Radius: 2, Material: ivory},
+			&Sphere{Center: &vec3f{-1, -1.5, -12}, Radius: 2, Material: glass},
+			&Sphere{Center: &vec3f{1.5, -0.5, -18}, Radius: 3, Material: redRubber},
+			&Sphere{Center: &vec3f{7, 5, -18}, Radius: 4, Material: mirror},
+			&CheckerboardPlane{Y: -4, HalfSize: 10, NearZ: -10, FarZ: -30},
+		},
+		Lights: []*Light{
+			{Position: &vec3f{-20, 20, 20}, Intensity: 1.5},
+			{Position: &vec3f{30, 50, -25}, Intensity: 1.8},
+			{Position: &vec3f{30, 20, 30}, Intensity: 1.7},
+		},
 	}
 
+	scene.BuildBVH()
+
+	renderer := NewRenderer()
+	img := renderer.Render(scene)
+
 	mustWriteToDisk(img, "out.png")
 }
 
-func sceneIntersect(orig *vec3f, dir *vec3f, spheres []*Sphere) (bool, *vec3f, *vec3f, *Material) {
-	spheresDist := math.MaxFloat64
-	curMaterial := NewMaterial()
-	var hit *vec3f
+func sceneIntersect(orig *vec3f, dir *vec3f, scene *Scene) (bool, *vec3f, *vec3f, *Material) {
+	closestDist := math.MaxFloat64
 	var n *vec3f
-	for _, sphere := range spheres {
-		intersect, dist := sphere.RayIntersect(orig, dir)
-		if intersect && dist < spheresDist {
-			curMaterial = sphere.Material
-			hit = orig.Add(dir.MultiplyF(dist))
-			n = hit.Subtract(sphere.Center).Normalize()
-			spheresDist = dist
+	var material *Material
+
+	if scene.bvh != nil {
+		if intersect, dist, hitNormal, hitMaterial := scene.bvh.Intersect(orig, dir); intersect {
+			closestDist = dist
+			n = hitNormal
+			material = hitMaterial
 		}
-	}
-	checkboardDist := math.MaxFloat64
-	if math.Abs(dir.Y) > 1e-3 {
-		d := -(orig.Y + 4) / dir.Y
-		pt := orig.Add(dir.MultiplyF(d))
-		if d > 0 && math.Abs(pt.X) < 10 && pt.Z < -10 && pt.Z > -30 && d < spheresDist {
-			checkboardDist = d
-			hit = pt
-			n = &vec3f{0, 1, 0}
-			if (int(0.5+hit.X+1000)+int(0.5*hit.Z))&1 != 0 {
-				curMaterial.DiffuseColor = &(vec3f{1, 1, 1})
-			} else {
-				curMaterial.DiffuseColor = &(vec3f{1, 0.7, 0.3})
+		for _, hittable := range scene.unbounded {
+			if intersect, dist, hitNormal, hitMaterial := hittable.RayIntersect(orig, dir); intersect && dist < closestDist {
+				closestDist = dist
+				n = hitNormal
+				material = hitMaterial
 			}
-			curMaterial.DiffuseColor = curMaterial.DiffuseColor.MultiplyF(0.3)
 		}
+	} else {
+		for _, hittable := range scene.Hittables {
+			if intersect, dist, hitNormal, hitMaterial := hittable.RayIntersect(orig, dir); intersect && dist < closestDist {
+				closestDist = dist
+				n = hitNormal
+				material = hitMaterial
+			}
+		}
+	}
+
+	if closestDist >= 1000 {
+		return false, nil, nil, nil
+	}
+	return true, orig.Add(dir.MultiplyF(closestDist)), n, material
+}
+
+// trace dispatches a primary ray to the integrator selected by r.Integrator.
+func (r *Renderer) trace(orig *vec3f, dir *vec3f, scene *Scene) *vec3f {
+	switch r.Integrator {
+	case IntegratorPathTrace:
+		return r.pathTrace(orig, dir, scene, 0, &vec3f{1, 1, 1})
+	default:
+		return r.castRay(orig, dir, scene, 0)
 	}
-	return math.Min(spheresDist, checkboardDist) < 1000, hit, n, curMaterial
 }
 
-func castRay(orig *vec3f, dir *vec3f, spheres []*Sphere, lights []*Light, depth int) *vec3f {
-	intersect, point, n, intersectMaterial := sceneIntersect(orig, dir, spheres)
-	if depth > 4 || !intersect {
+// pathTrace is a Monte Carlo path tracer: at each bounce it either
+// terminates on an emissive surface (returning its emission scaled by the
+// accumulated throughput) or continues in a cosine-weighted random
+// direction over the hemisphere around the surface normal, attenuating
+// throughput by the surface's diffuse albedo. After minBounces, Russian
+// roulette uses max(throughput.X,Y,Z) as the survival probability so the
+// recursion terminates in expectation without biasing the result.
+func (r *Renderer) pathTrace(orig *vec3f, dir *vec3f, scene *Scene, depth int, throughput *vec3f) *vec3f {
+	intersect, point, n, material := sceneIntersect(orig, dir, scene)
+	if !intersect {
+		return &vec3f{0, 0, 0}
+	}
+	if material.Emissive != nil {
+		return material.Emissive.MultiplyVec(throughput)
+	}
+
+	newThroughput := throughput.MultiplyVec(material.DiffuseColor)
+	if depth >= minBounces {
+		survival := math.Max(newThroughput.X, math.Max(newThroughput.Y, newThroughput.Z))
+		if rand.Float64() > survival {
+			return &vec3f{0, 0, 0}
+		}
+		newThroughput = newThroughput.MultiplyF(1 / survival)
+	}
+
+	u1, u2 := rand.Float64(), rand.Float64()
+	radius := math.Sqrt(u1)
+	theta := 2 * math.Pi * u2
+	localDir := &vec3f{radius * math.Cos(theta), radius * math.Sin(theta), math.Sqrt(1 - u1)}
+
+	nt, nb := onb(n)
+	worldDir := nt.MultiplyF(localDir.X).Add(nb.MultiplyF(localDir.Y)).Add(n.MultiplyF(localDir.Z)).Normalize()
+	newOrig := point.Add(n.MultiplyF(1e-3))
+
+	return r.pathTrace(newOrig, worldDir, scene, depth+1, newThroughput)
+}
+
+// onb builds an orthonormal basis (tangent, bitangent) around n, which is
+// assumed to already be a unit vector.
+func onb(n *vec3f) (*vec3f, *vec3f) {
+	var nt *vec3f
+	if math.Abs(n.X) > math.Abs(n.Y) {
+		nt = (&vec3f{n.Z, 0, -n.X}).Normalize()
+	} else {
+		nt = (&vec3f{0, -n.Z, n.Y}).Normalize()
+	}
+	nb := n.Cross(nt)
+	return nt, nb
+}
+
+// castRay is the Whitted-style recursive integrator: it shades the first
+// surface hit with direct lighting, then recurses into reflection and
+// refraction rays up to r.MaxDepth.
+func (r *Renderer) castRay(orig *vec3f, dir *vec3f, scene *Scene, depth int) *vec3f {
+	intersect, point, n, intersectMaterial := sceneIntersect(orig, dir, scene)
+	if depth > r.MaxDepth || !intersect {
 		return &vec3f{55 / 255.0, 176 / 255.0, 202 / 255.0}
 	}
 
@@ -165,7 +1094,7 @@ func castRay(orig *vec3f, dir *vec3f, spheres []*Sphere, lights []*Light, depth
 	} else {
 		reflectOrig = point.Add(n.MultiplyF(1e-3))
 	}
-	reflectColor := castRay(reflectOrig, reflectDir, spheres, lights, depth+1)
+	reflectColor := r.castRay(reflectOrig, reflectDir, scene, depth+1)
 
 	refractDir := refract(dir, n, intersectMaterial.RefractiveIndex, 1).Normalize()
 	var refractOrig *vec3f
@@ -174,11 +1103,11 @@ func castRay(orig *vec3f, dir *vec3f, spheres []*Sphere, lights []*Light, depth
 	} else {
 		refractOrig = point.Add(n.MultiplyF(1e-3))
 	}
-	refractColor := castRay(refractOrig, refractDir, spheres, lights, depth+1)
+	refractColor := r.castRay(refractOrig, refractDir, scene, depth+1)
 
 	diffuseLightIntensity := 0.0
 	specularLightIntensity := 0.0
-	for _, light := range lights {
+	for _, light := range scene.Lights {
 		lightDir := (light.Position.Subtract(point)).Normalize()
 		lightDistance := (light.Position.Subtract(point)).norm()
 
@@ -188,7 +1117,7 @@ func castRay(orig *vec3f, dir *vec3f, spheres []*Sphere, lights []*Light, depth
 		} else {
 			shadowOrig = point.Add(n.MultiplyF(1e-3))
 		}
-		shadowIntersect, shadowPoint, _, _ := sceneIntersect(shadowOrig, lightDir, spheres)
+		shadowIntersect, shadowPoint, _, _ := sceneIntersect(shadowOrig, lightDir, scene)
 		if shadowIntersect && shadowPoint.Subtract(shadowOrig).norm() < lightDistance {
 			continue
 		}
@@ -204,10 +1133,10 @@ func castRay(orig *vec3f, dir *vec3f, spheres []*Sphere, lights []*Light, depth
 		Add(refractColor.MultiplyF(intersectMaterial.Albedo.T))
 }
 
-// RayIntersect determines if the provided ray interescts with s.
-// If an interesction occurs, the distance is also returns.
-// If not intersections, a zero value for distance is returned
-func (s Sphere) RayIntersect(orig *vec3f, dir *vec3f) (bool, float64) {
+// intersectDistance determines if the provided ray intersects with s.
+// If an intersection occurs, the distance is also returned.
+// If no intersection occurs, a zero value for distance is returned.
+func (s Sphere) intersectDistance(orig *vec3f, dir *vec3f) (bool, float64) {
 	l := s.Center.Subtract(orig)
 	tca := l.Multiply(dir)
 	d2 := l.Multiply(l) - tca*tca
@@ -226,6 +1155,23 @@ func (s Sphere) RayIntersect(orig *vec3f, dir *vec3f) (bool, float64) {
 	return true, t
 }
 
+// RayIntersect implements Hittable for Sphere.
+func (s Sphere) RayIntersect(orig *vec3f, dir *vec3f) (bool, float64, *vec3f, *Material) {
+	intersect, dist := s.intersectDistance(orig, dir)
+	if !intersect {
+		return false, 0, nil, nil
+	}
+	hit := orig.Add(dir.MultiplyF(dist))
+	n := hit.Subtract(s.Center).Normalize()
+	return true, dist, n, s.Material
+}
+
+// Bounds implements Bounded for Sphere.
+func (s Sphere) Bounds() AABB {
+	r := &vec3f{s.Radius, s.Radius, s.Radius}
+	return AABB{Min: s.Center.Subtract(r), Max: s.Center.Add(r)}
+}
+
 func mustWriteToDisk(img image.Image, filename string) {
 	// Create output file
 	f, err := os.Create(filename)
@@ -304,6 +1250,20 @@ func (v *vec3f) Subtract(rhs *vec3f) *vec3f {
 	return ret
 }
 
+// MultiplyVec returns the component-wise (Hadamard) product of v and rhs.
+func (v *vec3f) MultiplyVec(rhs *vec3f) *vec3f {
+	return &vec3f{v.X * rhs.X, v.Y * rhs.Y, v.Z * rhs.Z}
+}
+
+// Cross returns the cross product of v and rhs.
+func (v *vec3f) Cross(rhs *vec3f) *vec3f {
+	return &vec3f{
+		v.Y*rhs.Z - v.Z*rhs.Y,
+		v.Z*rhs.X - v.X*rhs.Z,
+		v.X*rhs.Y - v.Y*rhs.X,
+	}
+}
+
 func (v *vec3f) Normalize() *vec3f {
 	return v.MultiplyF(1 / v.norm())
 }
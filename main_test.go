@@ -0,0 +1,293 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTriangleRayIntersect(t *testing.T) {
+	tri := Triangle{
+		V0:       &vec3f{-1, -1, -5},
+		V1:       &vec3f{1, -1, -5},
+		V2:       &vec3f{0, 1, -5},
+		Material: ivory,
+	}
+
+	tests := []struct {
+		name      string
+		orig, dir *vec3f
+		wantHit   bool
+		wantDist  float64
+	}{
+		{
+			name:     "hits center of the triangle",
+			orig:     &vec3f{0, -0.3, 0},
+			dir:      &vec3f{0, 0, -1},
+			wantHit:  true,
+			wantDist: 5,
+		},
+		{
+			name:    "misses outside the triangle",
+			orig:    &vec3f{0, 5, 0},
+			dir:     &vec3f{0, 0, -1},
+			wantHit: false,
+		},
+		{
+			name:    "misses when ray points away from the plane",
+			orig:    &vec3f{0, -0.3, 0},
+			dir:     &vec3f{0, 0, 1},
+			wantHit: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			hit, dist, n, material := tri.RayIntersect(tc.orig, tc.dir)
+			if hit != tc.wantHit {
+				t.Fatalf("RayIntersect() hit = %v, want %v", hit, tc.wantHit)
+			}
+			if !tc.wantHit {
+				return
+			}
+			if math.Abs(dist-tc.wantDist) > 1e-9 {
+				t.Errorf("RayIntersect() dist = %v, want %v", dist, tc.wantDist)
+			}
+			if n == nil || material != tri.Material {
+				t.Errorf("RayIntersect() returned n=%v material=%v on a hit", n, material)
+			}
+		})
+	}
+}
+
+func TestLoadOBJ(t *testing.T) {
+	obj := `# a unit quad, fan-triangulated, with a v/vt/vn face and a negative index
+v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+f 1/1/1 2/2/1 3/3/1 4/4/1
+f -4 -3 -1
+`
+	path := filepath.Join(t.TempDir(), "quad.obj")
+	if err := os.WriteFile(path, []byte(obj), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	mesh, err := LoadOBJ(path)
+	if err != nil {
+		t.Fatalf("LoadOBJ() error = %v", err)
+	}
+
+	// The quad fan-triangulates into 2 triangles, plus 1 from the
+	// 3-vertex negative-index face.
+	if len(mesh.Triangles) != 3 {
+		t.Fatalf("len(mesh.Triangles) = %d, want 3", len(mesh.Triangles))
+	}
+
+	for i, tri := range mesh.Triangles {
+		if tri.Material == nil || tri.Material.DiffuseColor == nil {
+			t.Fatalf("mesh.Triangles[%d].Material.DiffuseColor = nil, want a renderable default", i)
+		}
+	}
+
+	first := mesh.Triangles[0]
+	wantV0, wantV1, wantV2 := &vec3f{0, 0, 0}, &vec3f{1, 0, 0}, &vec3f{1, 1, 0}
+	if *first.V0 != *wantV0 || *first.V1 != *wantV1 || *first.V2 != *wantV2 {
+		t.Errorf("first triangle = {%v %v %v}, want {%v %v %v}", first.V0, first.V1, first.V2, wantV0, wantV1, wantV2)
+	}
+
+	// "-4 -3 -1" resolves (relative to the 4 vertices parsed so far) to
+	// vertices 1, 2, 4.
+	last := mesh.Triangles[2]
+	wantV0, wantV1, wantV2 = &vec3f{0, 0, 0}, &vec3f{1, 0, 0}, &vec3f{0, 1, 0}
+	if *last.V0 != *wantV0 || *last.V1 != *wantV1 || *last.V2 != *wantV2 {
+		t.Errorf("negative-index triangle = {%v %v %v}, want {%v %v %v}", last.V0, last.V1, last.V2, wantV0, wantV1, wantV2)
+	}
+}
+
+func TestLoadOBJInvalidFaceIndex(t *testing.T) {
+	obj := "v 0 0 0\nf 1 2 3\n"
+	path := filepath.Join(t.TempDir(), "bad.obj")
+	if err := os.WriteFile(path, []byte(obj), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadOBJ(path); err == nil {
+		t.Fatal("LoadOBJ() error = nil, want an out-of-range face index error")
+	}
+}
+
+func TestCameraRayPinhole(t *testing.T) {
+	cam := NewCamera(&vec3f{0, 0, 0}, &vec3f{0, 0, -1}, &vec3f{0, 1, 0}, 90, 1, 0, 1)
+
+	orig, dir := cam.Ray(0.5, 0.5)
+	if *orig != (vec3f{0, 0, 0}) {
+		t.Errorf("center ray origin = %v, want {0 0 0} (no aperture, no jitter)", orig)
+	}
+	if math.Abs(dir.X) > 1e-9 || math.Abs(dir.Y) > 1e-9 || dir.Z >= 0 {
+		t.Errorf("center ray dir = %v, want to point straight down -Z", dir)
+	}
+
+	_, leftDir := cam.Ray(0, 0.5)
+	_, rightDir := cam.Ray(1, 0.5)
+	if leftDir.X >= rightDir.X {
+		t.Errorf("left-edge ray X (%v) should be less than right-edge ray X (%v)", leftDir.X, rightDir.X)
+	}
+}
+
+// TestBVHIntersectMatchesLinearScan is a regression test for a bug where
+// the backward suffix-bounds sweep in BVH.build referenced its own
+// zero-value slot instead of the already-computed neighbor, crashing
+// NewBVH with a nil-pointer panic for any split over more than
+// bvhLeafSize primitives.
+func TestBVHIntersectMatchesLinearScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	var spheres []Bounded
+	var all []Hittable
+	for i := 0; i < 5*bvhLeafSize; i++ {
+		s := &Sphere{
+			Center:   &vec3f{rng.Float64()*20 - 10, rng.Float64()*20 - 10, rng.Float64()*20 - 10},
+			Radius:   0.5 + rng.Float64(),
+			Material: NewMaterial(),
+		}
+		spheres = append(spheres, s)
+		all = append(all, s)
+	}
+
+	bvh := NewBVH(spheres)
+
+	for i := 0; i < 500; i++ {
+		orig := &vec3f{0, 0, 30}
+		dir := (&vec3f{rng.Float64()*2 - 1, rng.Float64()*2 - 1, -1}).Normalize()
+
+		gotHit, gotDist, _, _ := bvh.Intersect(orig, dir)
+
+		wantHit := false
+		wantDist := math.MaxFloat64
+		for _, h := range all {
+			if hit, dist, _, _ := h.RayIntersect(orig, dir); hit && dist < wantDist {
+				wantHit, wantDist = true, dist
+			}
+		}
+
+		if gotHit != wantHit {
+			t.Fatalf("ray %d: BVH.Intersect() hit = %v, want %v", i, gotHit, wantHit)
+		}
+		if wantHit && math.Abs(gotDist-wantDist) > 1e-9 {
+			t.Fatalf("ray %d: BVH.Intersect() dist = %v, want %v", i, gotDist, wantDist)
+		}
+	}
+}
+
+// TestRenderSmoke drives Renderer.Render end-to-end over a tiny scene,
+// exercising the tile worker pool, the castRay integrator, and the
+// tonemap/gamma/bloom pipeline in Develop together.
+func TestRenderSmoke(t *testing.T) {
+	scene := &Scene{
+		Hittables: []Hittable{
+			&Sphere{Center: &vec3f{0, 0, -5}, Radius: 2, Material: ivory},
+			&CheckerboardPlane{Y: -2, HalfSize: 10, NearZ: -1, FarZ: -20},
+		},
+		Lights: []*Light{
+			{Position: &vec3f{-10, 10, 10}, Intensity: 1.5},
+		},
+	}
+
+	r := NewRenderer()
+	r.Width, r.Height = 16, 16
+	r.SamplesPerPixel = 2
+	r.TileSize = 8
+	r.NumWorkers = 2
+	r.Camera = NewCamera(&vec3f{0, 0, 0}, &vec3f{0, 0, -1}, &vec3f{0, 1, 0}, 60, 1, 0, 1)
+
+	img := r.Render(scene)
+
+	if got := img.Bounds(); got.Dx() != r.Width || got.Dy() != r.Height {
+		t.Fatalf("Render() image bounds = %v, want %dx%d", got, r.Width, r.Height)
+	}
+
+	// The sphere fills the center of the frame and the background fills
+	// the corners, so the two should develop to different colors. A
+	// degenerate render (e.g. every ray erroring out to the same
+	// fallback color) would make them equal.
+	center := img.RGBAAt(r.Width/2, r.Height/2)
+	corner := img.RGBAAt(0, 0)
+	if center == corner {
+		t.Errorf("Render() center pixel = %v, same as corner pixel %v, want the sphere and background to develop differently", center, corner)
+	}
+}
+
+// TestPathTraceEmissiveTermination checks that pathTrace terminates a path
+// as soon as it hits an emissive surface, returning the emission scaled by
+// the accumulated throughput rather than recursing further.
+func TestPathTraceEmissiveTermination(t *testing.T) {
+	light := NewMaterial()
+	light.Emissive = &vec3f{3, 2, 1}
+	scene := &Scene{
+		Hittables: []Hittable{
+			&Sphere{Center: &vec3f{0, 0, -5}, Radius: 2, Material: light},
+		},
+	}
+
+	r := NewRenderer()
+	throughput := &vec3f{0.5, 0.5, 0.5}
+	got := r.pathTrace(&vec3f{0, 0, 0}, &vec3f{0, 0, -1}, scene, 0, throughput)
+
+	want := light.Emissive.MultiplyVec(throughput)
+	if *got != *want {
+		t.Errorf("pathTrace() on an emissive hit = %v, want %v", got, want)
+	}
+}
+
+// TestPathTraceRussianRoulette checks the unbiased Russian-roulette
+// survival math past minBounces: a path that survives is rescaled by
+// 1/survival, so its eventual contribution has the same expectation as
+// the un-terminated path would have had. The test sets up a diffuse
+// surface whose attenuation exactly matches the survival probability, so
+// a surviving path's rescaled throughput returns to {1,1,1} by the time
+// it reaches the enclosing emissive sphere, and checks that every
+// non-terminated trial reproduces the light's emission exactly.
+func TestPathTraceRussianRoulette(t *testing.T) {
+	rand.Seed(1)
+
+	grey := NewMaterial()
+	grey.DiffuseColor = &vec3f{0.5, 0.5, 0.5}
+	light := NewMaterial()
+	light.Emissive = &vec3f{2, 2, 2}
+
+	scene := &Scene{
+		Hittables: []Hittable{
+			&Sphere{Center: &vec3f{0, 0, 0}, Radius: 1, Material: grey},
+			&Sphere{Center: &vec3f{0, 0, 0}, Radius: 1000, Material: light},
+		},
+	}
+
+	r := NewRenderer()
+	const trials = 2000
+	terminated, survived := 0, 0
+	for i := 0; i < trials; i++ {
+		got := r.pathTrace(&vec3f{0, 0, -2}, &vec3f{0, 0, 1}, scene, minBounces, &vec3f{1, 1, 1})
+		if *got == (vec3f{0, 0, 0}) {
+			terminated++
+			continue
+		}
+		survived++
+		if *got != *light.Emissive {
+			t.Fatalf("trial %d: surviving path returned %v, want exactly %v (unbiased by the 1/survival rescale)", i, got, light.Emissive)
+		}
+	}
+
+	// The grey surface attenuates throughput to {0.5,0.5,0.5}, so the
+	// survival probability is 0.5: roughly half the trials should
+	// terminate early.
+	if ratio := float64(terminated) / trials; ratio < 0.4 || ratio > 0.6 {
+		t.Errorf("terminated %d/%d trials (%.2f), want roughly 0.5", terminated, trials, ratio)
+	}
+	if survived == 0 {
+		t.Fatal("no trials survived Russian roulette; can't check unbiased emission")
+	}
+}